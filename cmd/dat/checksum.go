@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/mgutz/dat/dat"
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// bootstrapChecksumColumns adds the columns the checksum drift check and
+// audit trail need on dat__migrations, for installs whose dat__migrations
+// predates them. Safe to run on every invocation: Postgres 9.6+'s ADD COLUMN
+// IF NOT EXISTS is a no-op once the column already exists.
+func bootstrapChecksumColumns(conn runner.Connection) error {
+	_, err := conn.SQL(`
+		alter table dat__migrations
+			add column if not exists up_checksum text,
+			add column if not exists down_checksum text,
+			add column if not exists applied_at timestamptz,
+			add column if not exists applied_by text;
+	`).Exec()
+	return err
+}
+
+// loadMigrationScriptsForDrift reads each db-recorded migration's local
+// up.sql/down.sql (or its registered Go migration's source, if any) into a
+// throwaway *Migration, so checkMigrationDrift can hash the current local
+// content without needing the fully-loaded localMigrations that
+// runUpScripts/runDownScripts only populate lazily, one migration at a time,
+// as they run.
+func loadMigrationScriptsForDrift(options *AppOptions, dbMigrations []*Migration) []*Migration {
+	loaded := make([]*Migration, 0, len(dbMigrations))
+	for _, dbMigration := range dbMigrations {
+		m := &Migration{Name: dbMigration.Name}
+
+		upFilename := scriptFilename(options, m, "up.sql")
+		if upScript, err := readFileText(options, upFilename); err == nil {
+			m.UpScript = upScript
+		} else if reg, ok := dat.LookupMigration(m.Name); ok {
+			m.UpScript = dat.SourceText(m.Name, reg.UpSource)
+		}
+
+		downFilename := scriptFilename(options, m, "down.sql")
+		if downScript, err := readFileText(options, downFilename); err == nil {
+			m.DownScript = downScript
+		} else if reg, ok := dat.LookupMigration(m.Name); ok && reg.Down != nil {
+			m.DownScript = dat.SourceText(m.Name, reg.DownSource)
+		}
+
+		loaded = append(loaded, m)
+	}
+	return loaded
+}
+
+// checksum returns the hex-encoded SHA256 of s, or "" when s is empty so
+// migrations without a down.sql don't get a spurious checksum.
+func checksum(s string) string {
+	if s == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s))
+	return fmt.Sprintf("%x", sum)
+}
+
+// driftedMigration names one migration whose local up.sql/down.sql no
+// longer matches the checksum recorded when it was applied.
+type driftedMigration struct {
+	Name        string
+	UpDrifted   bool
+	DownDrifted bool
+}
+
+// checkMigrationDrift re-hashes each local migration's up.sql/down.sql (or
+// its registered Go migration source text) and compares it against the
+// up_checksum/down_checksum recorded in dbMigrations, returning one entry
+// per migration whose local content has diverged.
+//
+// localMigrations must have UpScript/DownScript populated (unlike the
+// partial migrations returned by getPartialLocalMigrations).
+func checkMigrationDrift(localMigrations, dbMigrations []*Migration) []driftedMigration {
+	var drifted []driftedMigration
+
+	for _, dbMigration := range dbMigrations {
+		idx := migrationFindIndexOf(localMigrations, dbMigration.Name)
+		if idx == -1 {
+			// already reported by verifyMigrationsHistory
+			continue
+		}
+		localMigration := localMigrations[idx]
+
+		d := driftedMigration{Name: dbMigration.Name}
+		if dbMigration.UpChecksum != "" && checksum(localMigration.UpScript) != dbMigration.UpChecksum {
+			d.UpDrifted = true
+		}
+		if dbMigration.DownChecksum != "" && checksum(localMigration.DownScript) != dbMigration.DownChecksum {
+			d.DownDrifted = true
+		}
+
+		if d.UpDrifted || d.DownDrifted {
+			drifted = append(drifted, d)
+		}
+	}
+
+	return drifted
+}
+
+// verifyMigrationsChecksums is the checksum counterpart of
+// verifyMigrationsHistory: it fails the command if any applied migration's
+// local source has changed since it ran, unless ctx.Options.AllowDrift is
+// set. localMigrations must be fully loaded (UpScript/DownScript populated),
+// not the partial list from getPartialLocalMigrations.
+func verifyMigrationsChecksums(ctx *AppContext, localMigrations, dbMigrations []*Migration) error {
+	drifted := checkMigrationDrift(localMigrations, dbMigrations)
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	for _, d := range drifted {
+		logger.Info("Migration %s has drifted from what was applied to %s database.\n", d.Name, ctx.Options.Connection.Database)
+	}
+
+	if ctx.Options.AllowDrift {
+		return nil
+	}
+
+	return fmt.Errorf("%d migration(s) have drifted from the applied history, pass --allow-drift to continue anyway", len(drifted))
+}
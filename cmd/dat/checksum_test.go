@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestChecksum(t *testing.T) {
+	if got := checksum(""); got != "" {
+		t.Errorf("checksum(\"\") = %q, want empty", got)
+	}
+
+	a := checksum("create table foo (id int);")
+	b := checksum("create table foo (id int);")
+	if a != b {
+		t.Errorf("checksum is not deterministic: %q != %q", a, b)
+	}
+
+	c := checksum("create table foo (id int, name text);")
+	if a == c {
+		t.Errorf("checksum did not change when content changed")
+	}
+}
+
+func TestCheckMigrationDrift(t *testing.T) {
+	local := []*Migration{
+		{Name: "20200101-a", UpScript: "up a", DownScript: "down a"},
+		{Name: "20200102-b", UpScript: "up b changed", DownScript: "down b"},
+		{Name: "20200103-c", UpScript: "up c", DownScript: ""},
+	}
+
+	db := []*Migration{
+		{Name: "20200101-a", UpChecksum: checksum("up a"), DownChecksum: checksum("down a")},
+		{Name: "20200102-b", UpChecksum: checksum("up b"), DownChecksum: checksum("down b")},
+		{Name: "20200103-c", UpChecksum: checksum("up c"), DownChecksum: ""},
+		{Name: "20200104-missing", UpChecksum: checksum("up missing"), DownChecksum: ""},
+	}
+
+	drifted := checkMigrationDrift(local, db)
+
+	if len(drifted) != 1 {
+		t.Fatalf("checkMigrationDrift returned %d entries, want 1: %+v", len(drifted), drifted)
+	}
+
+	d := drifted[0]
+	if d.Name != "20200102-b" {
+		t.Errorf("drifted migration = %q, want 20200102-b", d.Name)
+	}
+	if !d.UpDrifted {
+		t.Errorf("expected UpDrifted=true for 20200102-b")
+	}
+	if d.DownDrifted {
+		t.Errorf("expected DownDrifted=false for 20200102-b")
+	}
+}
+
+func TestVerifyMigrationsChecksums(t *testing.T) {
+	ctx := &AppContext{Options: &AppOptions{Connection: Connection{Database: "test"}}}
+
+	local := []*Migration{
+		{Name: "20200101-a", UpScript: "up a changed"},
+	}
+	db := []*Migration{
+		{Name: "20200101-a", UpChecksum: checksum("up a")},
+	}
+
+	if err := verifyMigrationsChecksums(ctx, local, db); err == nil {
+		t.Errorf("expected drift error, got nil")
+	}
+
+	ctx.Options.AllowDrift = true
+	if err := verifyMigrationsChecksums(ctx, local, db); err != nil {
+		t.Errorf("expected nil error with AllowDrift set, got %v", err)
+	}
+}
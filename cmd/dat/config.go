@@ -7,6 +7,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	conf "github.com/mgutz/configpipe"
 )
@@ -30,6 +31,53 @@ type AppOptions struct {
 	TablePrefix    string
 	Vendor         string
 	UnparsedArgs   []string
+
+	// AllowDrift, when set, downgrades checksum drift errors from
+	// verifyMigrationsChecksums to warnings (the --allow-drift flag).
+	AllowDrift bool
+
+	// LockTimeout bounds how long UpMigrations/DownMigrations wait to
+	// acquire the Postgres advisory migration lock before giving up (the
+	// --lock-timeout flag). Zero blocks forever.
+	LockTimeout time.Duration
+
+	// LogFormat selects how errors are rendered. "json" emits a SQLError as
+	// a single line of JSON (the --log-format=json flag); any other value,
+	// including the default "", renders a colored human-readable block.
+	LogFormat string
+
+	// Source is where migration and sproc files are read from. When nil, it
+	// defaults to DirMigrationSource, reading from the local filesystem.
+	// Applications embedding dat can set this to an FSMigrationSource backed
+	// by an embed.FS to ship migrations compiled into the binary.
+	Source MigrationSource
+
+	// Progress selects how migration runs report progress: "plain" for the
+	// historical log lines, "bar" for an mpb progress bar, or "auto" (the
+	// default) which picks "bar" only when stdout is a TTY.
+	Progress string
+
+	// Observer, when set, overrides the Observer picked from Progress.
+	// Mainly useful for tests.
+	Observer Observer
+}
+
+// observer returns options.Observer, falling back to one picked from
+// options.Progress via newObserver.
+func (options *AppOptions) observer(totalMigrations int) Observer {
+	if options.Observer != nil {
+		return options.Observer
+	}
+	return newObserver(options.Progress, totalMigrations)
+}
+
+// source returns options.Source, falling back to DirMigrationSource when
+// none was configured.
+func (options *AppOptions) source() MigrationSource {
+	if options.Source == nil {
+		return DirMigrationSource{}
+	}
+	return options.Source
 }
 
 func parseOptions(config *conf.Configuration) (*AppOptions, error) {
@@ -47,6 +95,9 @@ func parseOptions(config *conf.Configuration) (*AppOptions, error) {
 		SprocsDir:      config.AsString("sprocsDir"),
 		TablePrefix:    config.OrString("tablePrefix", "dat"),
 		Vendor:         config.OrString("vendor", "postgres"),
+		AllowDrift:     config.AsString("allowDrift") != "",
+		LogFormat:      config.OrString("logFormat", ""),
+		Progress:       config.OrString("progress", "auto"),
 	}
 
 	if options.DumpsDir == "" {
@@ -57,6 +108,14 @@ func parseOptions(config *conf.Configuration) (*AppOptions, error) {
 		options.SprocsDir = filepath.Join(options.MigrationsDir, "sprocs")
 	}
 
+	if raw := config.AsString("lockTimeout"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, err
+		}
+		options.LockTimeout = d
+	}
+
 	// on an error, keep it at zero value, it is checked outside
 	unparsed, err := config.StringArray("_unparsed")
 	if err == nil {
@@ -101,4 +160,4 @@ func loadConfig() (*conf.Configuration, error) {
 		// use custom filter to decrypt encrypted values
 		conf.FilterFunc(decryptor),
 	)
-}
\ No newline at end of file
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// Direction indicates which way a migration plan should be applied.
+type Direction int
+
+const (
+	// DirectionUp plans migrations that have not yet been applied.
+	DirectionUp Direction = iota
+	// DirectionDown plans migrations that have already been applied, in
+	// reverse order.
+	DirectionDown
+)
+
+// PlanError is returned by PlanMigrations when the database has a record of
+// a migration whose local folder can no longer be found. This mirrors the
+// warning verifyMigrationsHistory already prints, but as a typed error so
+// callers of PlanMigrations can fail fast instead of silently skipping it.
+type PlanError struct {
+	Name string
+}
+
+func (e *PlanError) Error() string {
+	return fmt.Sprintf("migration %s was applied to the database but its local folder is missing", e.Name)
+}
+
+// IrreversibleMigrationError is returned when a plan requires running
+// down.sql for a migration that was recorded without one.
+type IrreversibleMigrationError struct {
+	Name string
+}
+
+func (e *IrreversibleMigrationError) Error() string {
+	return fmt.Sprintf("migration %s has no down_script recorded and cannot be rolled back", e.Name)
+}
+
+// PlanMigrations diffs local against db and builds an ordered list of
+// migrations to apply in the given direction.
+//
+// For DirectionUp, local migrations not yet present in db are returned in
+// ascending (chronological) order. For DirectionDown, db migrations are
+// returned in descending (most recently applied first) order, as the db rows
+// themselves (carrying their recorded down_script), each checked against
+// local so a missing folder is reported as a PlanError.
+//
+// limit caps the number of migrations returned; limit <= 0 means no limit.
+func PlanMigrations(local, db []*Migration, dir Direction, limit int) ([]*Migration, error) {
+	var plan []*Migration
+
+	switch dir {
+	case DirectionUp:
+		for _, dbMigration := range db {
+			if migrationFindIndexOf(local, dbMigration.Name) == -1 {
+				return nil, &PlanError{Name: dbMigration.Name}
+			}
+		}
+		for _, localMigration := range local {
+			if migrationFindIndexOf(db, localMigration.Name) == -1 {
+				plan = append(plan, localMigration)
+			}
+		}
+	case DirectionDown:
+		for i := len(db) - 1; i >= 0; i-- {
+			dbMigration := db[i]
+			idx := migrationFindIndexOf(local, dbMigration.Name)
+			if idx == -1 {
+				return nil, &PlanError{Name: dbMigration.Name}
+			}
+
+			if dbMigration.DownScript == "" {
+				return nil, &IrreversibleMigrationError{Name: dbMigration.Name}
+			}
+
+			plan = append(plan, dbMigration)
+		}
+	default:
+		return nil, fmt.Errorf("unknown migration direction: %d", dir)
+	}
+
+	if limit > 0 && len(plan) > limit {
+		plan = plan[:limit]
+	}
+
+	return plan, nil
+}
+
+// planMigrationsTo trims a DirectionDown plan so it stops just after `to`,
+// the name of the migration to roll back to. An empty to rolls back
+// everything.
+func planMigrationsTo(plan []*Migration, to string) ([]*Migration, error) {
+	if to == "" {
+		return plan, nil
+	}
+
+	for i, migration := range plan {
+		if migration.Name == to {
+			return plan[:i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("migration %s is not in the applied history", to)
+}
+
+// runDownScripts runs a single migration's down script inside a transaction
+// and removes its row from dat__migrations. It is the rollback counterpart
+// of runUpScripts.
+//
+// It runs migration.DownScript, the down_script recorded in dat__migrations
+// (which PlanMigrations already checked is non-empty), not a fresh read of
+// down.sql off disk -- down.sql may have been edited or deleted since the
+// migration was applied, and rollback must run what was actually recorded.
+// down.sql on disk is read only when no down_script was recorded.
+func runDownScripts(ctx *AppContext, conn runner.Connection, migration *Migration, observer Observer) error {
+	downFilename := scriptFilename(ctx.Options, migration, "down.sql")
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.AutoRollback()
+
+	if hasGoMigration(migration.Name) {
+		// a Go migration's DownScript only holds dat.SourceText's stable
+		// identifier, not runnable SQL, so it must dispatch to the
+		// registered function rather than execScript.
+		if err := runGoDownScript(migration, tx); err != nil {
+			return err
+		}
+	} else if migration.DownScript != "" {
+		if err := execScript(ctx, downFilename, tx, migration.DownScript, observer); err != nil {
+			return err
+		}
+	} else if downScript, err := readFileText(ctx.Options, downFilename); err == nil {
+		if err := execScript(ctx, downFilename, tx, downScript, observer); err != nil {
+			return err
+		}
+	} else {
+		return &IrreversibleMigrationError{Name: migration.Name}
+	}
+
+	_, err = tx.SQL(
+		`delete from dat__migrations where name = $1;`,
+		migration.Name,
+	).Exec()
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DownMigrations rolls back up to n migrations (n <= 0 means all), or down to
+// and including just after the migration named to, whichever is given. It
+// holds the Postgres advisory migration lock for the duration of the run,
+// the same lock UpMigrations takes, so up and down runs never interleave.
+func DownMigrations(ctx *AppContext, adapter *PostgresAdapter, db *runner.DB, localMigrations, dbMigrations []*Migration, n int, to string) error {
+	plan, err := PlanMigrations(localMigrations, dbMigrations, DirectionDown, 0)
+	if err != nil {
+		return err
+	}
+
+	plan, err = planMigrationsTo(plan, to)
+	if err != nil {
+		return err
+	}
+
+	if to == "" && n > 0 && len(plan) > n {
+		plan = plan[:n]
+	}
+
+	if err := verifyMigrationsChecksums(ctx, loadMigrationScriptsForDrift(ctx.Options, dbMigrations), dbMigrations); err != nil {
+		return err
+	}
+
+	release, err := adapter.AcquireMigrationLock(db, ctx.Options, ctx.Options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := release(); err != nil {
+			logger.Error("failed to release migration lock: %s\n", err)
+		}
+	}()
+
+	observer := ctx.Options.observer(len(plan))
+	if p, ok := observer.(*ProgressObserver); ok {
+		defer p.Wait()
+	}
+
+	for _, migration := range plan {
+		logger.Info("reverting %s\n", migration.Name)
+		observer.OnMigrationStart(migration)
+		err := runDownScripts(ctx, db, migration, observer)
+		observer.OnMigrationEnd(migration, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// parseDownArgs parses the arguments to `dat down`: an optional bare integer
+// N (roll back the N most recently applied migrations), or --to <name> /
+// --to=<name> (roll back down to and including just after the named
+// migration). Neither given rolls back everything. The arguments are parsed
+// by hand rather than by pulling in a flag library for one subcommand.
+func parseDownArgs(args []string) (n int, to string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--to":
+			if i+1 >= len(args) {
+				return 0, "", fmt.Errorf("--to requires a migration name")
+			}
+			i++
+			to = args[i]
+		case strings.HasPrefix(arg, "--to="):
+			to = strings.TrimPrefix(arg, "--to=")
+		default:
+			parsed, convErr := strconv.Atoi(arg)
+			if convErr != nil {
+				return 0, "", fmt.Errorf("unrecognized argument to dat down: %s", arg)
+			}
+			n = parsed
+		}
+	}
+
+	return n, to, nil
+}
+
+// getDBMigrations loads every migration dat has recorded as applied, in
+// ascending (chronological) order, so PlanMigrations can diff them against
+// local and runDownScripts has down_script to roll back.
+func getDBMigrations(conn runner.Connection) ([]*Migration, error) {
+	var migrations []*Migration
+	err := conn.SQL(
+		`select name, up_script, down_script, no_tx_script, up_checksum, down_checksum
+		from dat__migrations
+		order by name asc;`,
+	).QueryStructs(&migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+// runDown is the `dat down` subcommand: it loads local and applied
+// migrations and rolls back via DownMigrations, using the N/--to arguments
+// parsed from ctx.Options.UnparsedArgs.
+func runDown(ctx *AppContext) error {
+	n, to, err := parseDownArgs(ctx.Options.UnparsedArgs)
+	if err != nil {
+		return err
+	}
+
+	adapter, db, err := getAdapterAndDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	localMigrations, err := getPartialLocalMigrations(ctx.Options)
+	if err != nil {
+		return err
+	}
+
+	dbMigrations, err := getDBMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	return DownMigrations(ctx, adapter, db, localMigrations, dbMigrations, n, to)
+}
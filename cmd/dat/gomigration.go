@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mgutz/dat/dat"
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// hasGoMigration reports whether a migration was registered in code for
+// name via dat.AddMigration.
+func hasGoMigration(name string) bool {
+	_, ok := dat.LookupMigration(name)
+	return ok
+}
+
+// runGoUpScript runs a registered Go up migration instead of up.sql.
+func runGoUpScript(migration *Migration, tx runner.Connection) error {
+	reg, ok := dat.LookupMigration(migration.Name)
+	if !ok {
+		return fmt.Errorf("no Go migration registered for %s", migration.Name)
+	}
+
+	if err := reg.Up(tx); err != nil {
+		return err
+	}
+
+	migration.UpScript = dat.SourceText(migration.Name, reg.UpSource)
+	return nil
+}
+
+// runGoDownScript runs a registered Go down migration instead of down.sql.
+func runGoDownScript(migration *Migration, tx runner.Connection) error {
+	reg, ok := dat.LookupMigration(migration.Name)
+	if !ok || reg.Down == nil {
+		return &IrreversibleMigrationError{Name: migration.Name}
+	}
+
+	return reg.Down(tx)
+}
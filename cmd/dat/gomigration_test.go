@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mgutz/dat/dat"
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+func TestHasGoMigration(t *testing.T) {
+	dat.AddMigration("20200101-has-go-migration", func(tx runner.Connection) error { return nil }, nil, "up", "")
+
+	if !hasGoMigration("20200101-has-go-migration") {
+		t.Error("hasGoMigration = false, want true for a registered migration")
+	}
+	if hasGoMigration("20200101-does-not-exist") {
+		t.Error("hasGoMigration = true, want false for an unregistered migration")
+	}
+}
+
+func TestRunGoUpScriptSetsUpScript(t *testing.T) {
+	dat.AddMigration("20200102-run-up", func(tx runner.Connection) error { return nil }, nil, "the up source", "")
+
+	migration := &Migration{Name: "20200102-run-up"}
+	if err := runGoUpScript(migration, nil); err != nil {
+		t.Fatalf("runGoUpScript returned error: %v", err)
+	}
+	if migration.UpScript != dat.SourceText("20200102-run-up", "the up source") {
+		t.Errorf("runGoUpScript did not set UpScript to the registered source's stable identifier")
+	}
+}
+
+func TestRunGoUpScriptPropagatesError(t *testing.T) {
+	wantErr := errors.New("boom")
+	dat.AddMigration("20200103-run-up-err", func(tx runner.Connection) error { return wantErr }, nil, "up", "")
+
+	migration := &Migration{Name: "20200103-run-up-err"}
+	if err := runGoUpScript(migration, nil); err != wantErr {
+		t.Errorf("runGoUpScript = %v, want %v", err, wantErr)
+	}
+}
+
+func TestRunGoDownScriptIrreversible(t *testing.T) {
+	dat.AddMigration("20200104-no-down", func(tx runner.Connection) error { return nil }, nil, "up", "")
+
+	migration := &Migration{Name: "20200104-no-down"}
+	err := runGoDownScript(migration, nil)
+	if _, ok := err.(*IrreversibleMigrationError); !ok {
+		t.Errorf("runGoDownScript = %T, want *IrreversibleMigrationError", err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// migrationLockKey returns a stable int64 key derived from TablePrefix so
+// concurrent `dat up`/`dat down` processes against the same database agree
+// on which pg_advisory_lock to contend for.
+func migrationLockKey(options *AppOptions) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(options.TablePrefix + "__migrations"))
+	return int64(h.Sum64())
+}
+
+// AcquireMigrationLock takes the Postgres advisory lock guarding migrations
+// for this database, blocking until it is acquired or timeout elapses (0
+// means block forever, matching pg_advisory_lock).
+//
+// Advisory locks belong to the backend connection that ran
+// pg_advisory_lock, not to the session in the abstract: against a
+// connection pool, acquiring and releasing via independent pool-routed
+// queries risks the unlock landing on a different pooled connection than
+// the lock did, leaving the real session stuck holding it forever. To avoid
+// that, this opens a transaction -- which database/sql pins to one
+// connection for its whole lifetime -- and holds it across the lock's
+// entire acquire/release window instead of issuing bare queries through db.
+//
+// The returned release func unlocks and commits the holding transaction. It
+// must be called, typically via defer, to release both.
+func (pg *PostgresAdapter) AcquireMigrationLock(db *runner.DB, options *AppOptions, timeout time.Duration) (release func() error, err error) {
+	key := migrationLockKey(options)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout <= 0 {
+		if _, err := tx.SQL(`select pg_advisory_lock($1);`, key).Exec(); err != nil {
+			tx.AutoRollback()
+			return nil, err
+		}
+	} else {
+		deadline := time.Now().Add(timeout)
+		for {
+			var locked bool
+			err := tx.SQL(`select pg_try_advisory_lock($1);`, key).QueryScalar(&locked)
+			if err != nil {
+				tx.AutoRollback()
+				return nil, err
+			}
+			if locked {
+				break
+			}
+			if time.Now().After(deadline) {
+				tx.AutoRollback()
+				return nil, fmt.Errorf("timed out after %s waiting for the %s migration lock, another process may be running migrations", timeout, options.TablePrefix)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	release = func() error {
+		if _, err := tx.SQL(`select pg_advisory_unlock($1);`, key).Exec(); err != nil {
+			tx.AutoRollback()
+			return err
+		}
+		return tx.Commit()
+	}
+	return release, nil
+}
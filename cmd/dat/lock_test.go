@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestMigrationLockKey(t *testing.T) {
+	a := migrationLockKey(&AppOptions{TablePrefix: "dat"})
+	b := migrationLockKey(&AppOptions{TablePrefix: "dat"})
+	if a != b {
+		t.Errorf("migrationLockKey is not deterministic for the same TablePrefix: %d != %d", a, b)
+	}
+
+	c := migrationLockKey(&AppOptions{TablePrefix: "other"})
+	if a == c {
+		t.Errorf("migrationLockKey did not change for a different TablePrefix")
+	}
+}
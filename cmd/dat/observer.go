@@ -0,0 +1,34 @@
+package main
+
+// Observer receives lifecycle events as a migration run progresses, so
+// callers can render progress differently (plain log lines, a progress bar,
+// ...) without execScript/runUpScripts/runDownScripts needing to know which.
+type Observer interface {
+	// OnMigrationStart fires once per migration, before its scripts run.
+	OnMigrationStart(migration *Migration)
+	// OnMigrationEnd fires once per migration, with the error (if any)
+	// that stopped it.
+	OnMigrationEnd(migration *Migration, err error)
+	// OnStatementStart fires before each statement split out of a script
+	// by reBatchSeparator.
+	OnStatementStart(filename string)
+	// OnStatementEnd fires after each statement, with its error (if any).
+	OnStatementEnd(filename string, err error)
+}
+
+// LogObserver is the default Observer. It is a no-op: execFile and
+// runUpScripts already print "filename ... OK" lines, and runUpScripts/runDownScripts
+// callers already log "applying/reverting <name>" around each migration.
+type LogObserver struct{}
+
+// OnMigrationStart implements Observer.
+func (LogObserver) OnMigrationStart(migration *Migration) {}
+
+// OnMigrationEnd implements Observer.
+func (LogObserver) OnMigrationEnd(migration *Migration, err error) {}
+
+// OnStatementStart implements Observer.
+func (LogObserver) OnStatementStart(filename string) {}
+
+// OnStatementEnd implements Observer.
+func (LogObserver) OnStatementEnd(filename string, err error) {}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestNewObserverModes(t *testing.T) {
+	if _, ok := newObserver("plain", 3).(LogObserver); !ok {
+		t.Error(`newObserver("plain", ...) did not return a LogObserver`)
+	}
+
+	if _, ok := newObserver("bar", 3).(*ProgressObserver); !ok {
+		t.Error(`newObserver("bar", ...) did not return a *ProgressObserver`)
+	}
+
+	// go test's stdout is not a TTY, so "auto" should fall back to plain.
+	if _, ok := newObserver("auto", 3).(LogObserver); !ok {
+		t.Error(`newObserver("auto", ...) did not fall back to LogObserver when stdout is not a TTY`)
+	}
+}
+
+func TestLogObserverIsNoop(t *testing.T) {
+	var o LogObserver
+	migration := &Migration{Name: "20200101-a"}
+
+	// None of these should panic; LogObserver relies on execFile/execScript
+	// and the up/down loops for their own logging.
+	o.OnMigrationStart(migration)
+	o.OnMigrationEnd(migration, nil)
+	o.OnStatementStart("up.sql")
+	o.OnStatementEnd("up.sql", nil)
+}
@@ -0,0 +1,113 @@
+package main
+
+import "testing"
+
+func TestPlanMigrationsUp(t *testing.T) {
+	local := []*Migration{
+		{Name: "20200101-a"},
+		{Name: "20200102-b"},
+		{Name: "20200103-c"},
+	}
+	db := []*Migration{
+		{Name: "20200101-a"},
+	}
+
+	plan, err := PlanMigrations(local, db, DirectionUp, 0)
+	if err != nil {
+		t.Fatalf("PlanMigrations returned error: %v", err)
+	}
+
+	if len(plan) != 2 || plan[0].Name != "20200102-b" || plan[1].Name != "20200103-c" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanMigrationsUpLimit(t *testing.T) {
+	local := []*Migration{
+		{Name: "20200101-a"},
+		{Name: "20200102-b"},
+	}
+
+	plan, err := PlanMigrations(local, nil, DirectionUp, 1)
+	if err != nil {
+		t.Fatalf("PlanMigrations returned error: %v", err)
+	}
+	if len(plan) != 1 || plan[0].Name != "20200101-a" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanMigrationsDown(t *testing.T) {
+	local := []*Migration{
+		{Name: "20200101-a"},
+		{Name: "20200102-b"},
+	}
+	db := []*Migration{
+		{Name: "20200101-a", DownScript: "down a"},
+		{Name: "20200102-b", DownScript: "down b"},
+	}
+
+	plan, err := PlanMigrations(local, db, DirectionDown, 0)
+	if err != nil {
+		t.Fatalf("PlanMigrations returned error: %v", err)
+	}
+
+	if len(plan) != 2 || plan[0].Name != "20200102-b" || plan[1].Name != "20200101-a" {
+		t.Fatalf("expected reverse order, got: %+v", plan)
+	}
+}
+
+func TestPlanMigrationsDownMissingLocalFolder(t *testing.T) {
+	db := []*Migration{
+		{Name: "20200101-a", DownScript: "down a"},
+	}
+
+	_, err := PlanMigrations(nil, db, DirectionDown, 0)
+	if err == nil {
+		t.Fatal("expected PlanError, got nil")
+	}
+	if _, ok := err.(*PlanError); !ok {
+		t.Fatalf("expected *PlanError, got %T: %v", err, err)
+	}
+}
+
+func TestPlanMigrationsDownIrreversible(t *testing.T) {
+	local := []*Migration{{Name: "20200101-a"}}
+	db := []*Migration{{Name: "20200101-a", DownScript: ""}}
+
+	_, err := PlanMigrations(local, db, DirectionDown, 0)
+	if err == nil {
+		t.Fatal("expected IrreversibleMigrationError, got nil")
+	}
+	if _, ok := err.(*IrreversibleMigrationError); !ok {
+		t.Fatalf("expected *IrreversibleMigrationError, got %T: %v", err, err)
+	}
+}
+
+func TestPlanMigrationsTo(t *testing.T) {
+	plan := []*Migration{
+		{Name: "20200103-c"},
+		{Name: "20200102-b"},
+		{Name: "20200101-a"},
+	}
+
+	trimmed, err := planMigrationsTo(plan, "20200102-b")
+	if err != nil {
+		t.Fatalf("planMigrationsTo returned error: %v", err)
+	}
+	if len(trimmed) != 1 || trimmed[0].Name != "20200103-c" {
+		t.Fatalf("unexpected trimmed plan: %+v", trimmed)
+	}
+
+	all, err := planMigrationsTo(plan, "")
+	if err != nil {
+		t.Fatalf("planMigrationsTo returned error: %v", err)
+	}
+	if len(all) != len(plan) {
+		t.Fatalf("expected empty `to` to return the full plan, got %+v", all)
+	}
+
+	if _, err := planMigrationsTo(plan, "does-not-exist"); err == nil {
+		t.Fatal("expected error for a `to` not in the plan")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+
+	isatty "github.com/mattn/go-isatty"
+	"github.com/vbauerster/mpb/v7"
+	"github.com/vbauerster/mpb/v7/decor"
+)
+
+// ProgressObserver renders migration progress with mpb: an overall
+// "N/M migrations applied" bar, plus one per-migration bar counting
+// statements as they're split out of each script by reBatchSeparator.
+type ProgressObserver struct {
+	progress   *mpb.Progress
+	overall    *mpb.Bar
+	currentBar *mpb.Bar
+}
+
+// NewProgressObserver creates a ProgressObserver for a run of total
+// migrations.
+func NewProgressObserver(total int) *ProgressObserver {
+	progress := mpb.New()
+	overall := progress.AddBar(int64(total),
+		mpb.PrependDecorators(decor.Name("migrations")),
+		mpb.AppendDecorators(decor.CountersNoUnit("%d / %d applied")),
+	)
+
+	return &ProgressObserver{progress: progress, overall: overall}
+}
+
+// OnMigrationStart implements Observer.
+func (o *ProgressObserver) OnMigrationStart(migration *Migration) {
+	o.currentBar = o.progress.AddBar(0,
+		mpb.PrependDecorators(decor.Name(migration.Name)),
+		mpb.AppendDecorators(decor.CurrentNoUnit("%d statements")),
+	)
+}
+
+// OnMigrationEnd implements Observer.
+func (o *ProgressObserver) OnMigrationEnd(migration *Migration, err error) {
+	if o.currentBar != nil {
+		o.currentBar.SetTotal(o.currentBar.Current(), true)
+		o.currentBar = nil
+	}
+	o.overall.Increment()
+}
+
+// OnStatementStart implements Observer.
+func (o *ProgressObserver) OnStatementStart(filename string) {}
+
+// OnStatementEnd implements Observer.
+func (o *ProgressObserver) OnStatementEnd(filename string, err error) {
+	if o.currentBar == nil {
+		return
+	}
+	o.currentBar.SetTotal(o.currentBar.Current()+1, false)
+	o.currentBar.Increment()
+}
+
+// Wait blocks until every bar has finished rendering. Call it after the
+// migration run completes.
+func (o *ProgressObserver) Wait() {
+	o.progress.Wait()
+}
+
+// newObserver picks an Observer for --progress={auto,plain,bar}. auto picks
+// bar only when stdout is a TTY, otherwise it falls back to plain log lines.
+func newObserver(mode string, totalMigrations int) Observer {
+	switch mode {
+	case "bar":
+		return NewProgressObserver(totalMigrations)
+	case "plain":
+		return LogObserver{}
+	default: // "auto"
+		if isatty.IsTerminal(os.Stdout.Fd()) {
+			return NewProgressObserver(totalMigrations)
+		}
+		return LogObserver{}
+	}
+}
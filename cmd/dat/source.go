@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MigrationSource abstracts where migration and sproc files are read from.
+// The default, DirMigrationSource, walks MigrationsDir on the local
+// filesystem. FSMigrationSource reads from an fs.FS instead, which lets
+// applications embed their migrations into the binary with embed.FS (or any
+// other fs.FS) for single-binary
+// deployments where MigrationsDir may not exist on disk.
+type MigrationSource interface {
+	// Dirs returns the names of directories found while walking root whose
+	// name matches reMigrationDir.
+	Dirs(root string) ([]string, error)
+	// Files returns the names of files found while walking dir whose name
+	// matches reSQLFile.
+	Files(dir string) ([]string, error)
+	// ReadFile returns the contents of path as a string.
+	ReadFile(path string) (string, error)
+}
+
+// DirMigrationSource is the default MigrationSource, backed by
+// filepath.Walk against the local filesystem.
+type DirMigrationSource struct{}
+
+// Dirs implements MigrationSource.
+func (DirMigrationSource) Dirs(root string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(root+"/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && reMigrationDir.MatchString(path) {
+			names = append(names, info.Name())
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Files implements MigrationSource.
+func (DirMigrationSource) Files(dir string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && reSQLFile.MatchString(info.Name()) {
+			names = append(names, info.Name())
+		}
+		return nil
+	})
+	return names, err
+}
+
+// ReadFile implements MigrationSource.
+func (DirMigrationSource) ReadFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// FSMigrationSource reads migrations from an fs.FS, such as an embed.FS
+// compiled into the binary.
+type FSMigrationSource struct {
+	FS fs.FS
+}
+
+// Dirs implements MigrationSource.
+func (s FSMigrationSource) Dirs(root string) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(s.FS, fsRoot(root), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && reMigrationDir.MatchString(path) {
+			names = append(names, d.Name())
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Files implements MigrationSource.
+func (s FSMigrationSource) Files(dir string) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(s.FS, fsRoot(dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && reSQLFile.MatchString(d.Name()) {
+			names = append(names, d.Name())
+		}
+		return nil
+	})
+	return names, err
+}
+
+// ReadFile implements MigrationSource.
+func (s FSMigrationSource) ReadFile(path string) (string, error) {
+	b, err := fs.ReadFile(s.FS, fsRoot(path))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// fsRoot normalizes a filepath-style path for use with fs.FS, which requires
+// slash-separated, non-empty, non-absolute paths ("." for the root).
+func fsRoot(path string) string {
+	path = filepath.ToSlash(path)
+	for len(path) > 0 && path[0] == '/' {
+		path = path[1:]
+	}
+	if path == "" {
+		return "."
+	}
+	return path
+}
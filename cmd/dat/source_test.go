@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSMigrationSourceRoundTrip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/20200101-a/up.sql":   {Data: []byte("select 1;")},
+		"migrations/20200101-a/down.sql": {Data: []byte("select 2;")},
+		"migrations/sprocs/foo.sql":      {Data: []byte("create function foo()")},
+	}
+	source := FSMigrationSource{FS: fsys}
+
+	dirs, err := source.Dirs("migrations")
+	if err != nil {
+		t.Fatalf("Dirs returned error: %v", err)
+	}
+	if len(dirs) != 1 || dirs[0] != "20200101-a" {
+		t.Fatalf("Dirs = %v, want [20200101-a]", dirs)
+	}
+
+	files, err := source.Files("migrations/sprocs")
+	if err != nil {
+		t.Fatalf("Files returned error: %v", err)
+	}
+	if len(files) != 1 || files[0] != "foo.sql" {
+		t.Fatalf("Files = %v, want [foo.sql]", files)
+	}
+
+	content, err := source.ReadFile("migrations/20200101-a/up.sql")
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if content != "select 1;" {
+		t.Fatalf("ReadFile = %q, want %q", content, "select 1;")
+	}
+}
+
+func TestFsRoot(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"", "."},
+		{"migrations", "migrations"},
+		{"/migrations", "migrations"},
+		{"///migrations/20200101-a", "migrations/20200101-a"},
+	}
+
+	for _, c := range cases {
+		if got := fsRoot(c.in); got != c.want {
+			t.Errorf("fsRoot(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// snippetContext is how many lines are shown before and after the offending
+// line in SQLError.Snippet.
+const snippetContext = 3
+
+// ANSI color codes used by SQLError.String(). Kept minimal: red for the
+// error itself, yellow for the caret pointing at the offending column.
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+func colorize(code, s string) string {
+	return code + s + ansiReset
+}
+
+// colorizeCaretLine wraps the "^" in a sourceSnippet's caret line in yellow.
+// Snippet itself is kept plain (it's also embedded verbatim in the JSON
+// rendering), so the coloring is applied only when rendering for a
+// terminal.
+func colorizeCaretLine(snippet string) string {
+	lines := strings.Split(snippet, "\n")
+	for i, l := range lines {
+		if idx := strings.LastIndex(l, "^"); idx != -1 && strings.TrimSpace(l[:idx]) == "|" {
+			lines[i] = l[:idx] + colorize(ansiYellow, "^")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SQLError is a structured report of a failed statement, built from the
+// pq.Error position Postgres returns on syntax and constraint errors. It
+// renders either as a colored human-readable block (String) or as JSON
+// (when --log-format=json is passed), so CI systems can parse migration
+// failures instead of scraping free-form text.
+type SQLError struct {
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// newSQLError builds a SQLError from a failed statement and the pq.Error
+// Postgres returned for it. ok is false when err is not a *pq.Error, in
+// which case callers should fall back to err.Error().
+func newSQLError(filename, script string, err error) (sqlErr *SQLError, ok bool) {
+	e, ok := err.(*pq.Error)
+	if !ok {
+		return nil, false
+	}
+
+	sqlErr = &SQLError{
+		Filename: filename,
+		Severity: e.Severity,
+		Code:     string(e.Code),
+		Message:  e.Message,
+		Detail:   e.Detail,
+		Hint:     e.Hint,
+	}
+
+	if e.Position != "" {
+		line, column, err := extractLineColumn(script, e.Position)
+		if err == nil {
+			sqlErr.Line = line
+			sqlErr.Column = column
+			sqlErr.Snippet = sourceSnippet(script, line, column)
+		}
+	}
+
+	return sqlErr, true
+}
+
+// sourceSnippet renders a ±snippetContext window of script around line, with
+// a caret pointing at column on the offending line.
+func sourceSnippet(script string, line, column int) string {
+	lines := strings.Split(script, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	start := line - snippetContext
+	if start < 1 {
+		start = 1
+	}
+	end := line + snippetContext
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	for n := start; n <= end; n++ {
+		fmt.Fprintf(&b, "%4d | %s\n", n, lines[n-1])
+		if n == line {
+			caret := column - 1
+			if caret < 0 {
+				caret = 0
+			}
+			fmt.Fprintf(&b, "     | %s^\n", strings.Repeat(" ", caret))
+		}
+	}
+	return b.String()
+}
+
+// String renders e as a human-readable, colored block: the error itself in
+// red, the snippet's caret line in yellow.
+func (e *SQLError) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s %s] %s", e.Severity, e.Code, colorize(ansiRed, e.Message))
+	if e.Filename != "" {
+		fmt.Fprintf(&b, " (%s:%d:%d)", e.Filename, e.Line, e.Column)
+	} else if e.Line > 0 {
+		fmt.Fprintf(&b, " at line=%d col=%d", e.Line, e.Column)
+	}
+	b.WriteString("\n")
+
+	if e.Snippet != "" {
+		b.WriteString(colorizeCaretLine(e.Snippet))
+	}
+	if e.Detail != "" {
+		fmt.Fprintf(&b, "DETAIL: %s\n", e.Detail)
+	}
+	if e.Hint != "" {
+		fmt.Fprintf(&b, "HINT: %s\n", e.Hint)
+	}
+
+	return b.String()
+}
+
+// JSON renders e as a single line of JSON for --log-format=json.
+func (e *SQLError) JSON() string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"message":%q}`, e.Message)
+	}
+	return string(b)
+}
+
+// sprintPQError formats err, which occurred while running statement, for the
+// configured log format. Non-pq.Error values fall back to err.Error().
+func sprintPQError(ctx *AppContext, filename, statement string, err error) string {
+	if err == nil {
+		return ""
+	}
+
+	sqlErr, ok := newSQLError(filename, statement, err)
+	if !ok {
+		return err.Error()
+	}
+
+	if ctx != nil && ctx.Options.LogFormat == "json" {
+		return sqlErr.JSON()
+	}
+	return sqlErr.String()
+}
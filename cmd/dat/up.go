@@ -0,0 +1,59 @@
+package main
+
+import (
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// UpMigrations applies every local migration not yet recorded in
+// dbMigrations, in order, holding the Postgres advisory migration lock for
+// the duration of the run so two `dat up` processes started concurrently
+// (e.g. parallel Kubernetes pod rollouts) can't both apply the same
+// migration.
+func UpMigrations(ctx *AppContext, adapter *PostgresAdapter, db *runner.DB, localMigrations, dbMigrations []*Migration) error {
+	plan, err := PlanMigrations(localMigrations, dbMigrations, DirectionUp, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyMigrationsChecksums(ctx, loadMigrationScriptsForDrift(ctx.Options, dbMigrations), dbMigrations); err != nil {
+		return err
+	}
+
+	release, err := adapter.AcquireMigrationLock(db, ctx.Options, ctx.Options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := release(); err != nil {
+			logger.Error("failed to release migration lock: %s\n", err)
+		}
+	}()
+
+	// dbMigrations was read before the lock was held; re-read it so that
+	// migrations another process applied meanwhile are not applied again.
+	dbMigrations, err = getDBMigrations(db)
+	if err != nil {
+		return err
+	}
+	plan, err = PlanMigrations(localMigrations, dbMigrations, DirectionUp, 0)
+	if err != nil {
+		return err
+	}
+
+	observer := ctx.Options.observer(len(plan))
+	if p, ok := observer.(*ProgressObserver); ok {
+		defer p.Wait()
+	}
+
+	for _, migration := range plan {
+		logger.Info("applying %s\n", migration.Name)
+		observer.OnMigrationStart(migration)
+		err := runUpScripts(ctx, db, migration, observer)
+		observer.OnMigrationEnd(migration, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
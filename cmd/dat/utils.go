@@ -10,8 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/davecgh/go-spew/spew"
-	"github.com/lib/pq"
 	"github.com/mgutz/str"
 
 	"github.com/mgutz/dat/dat"
@@ -63,24 +61,18 @@ func getAdapterAndDB(ctx *AppContext) (*PostgresAdapter, *runner.DB, error) {
 	}
 
 	err = adapter.Bootstrap(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = bootstrapChecksumColumns(db)
 	return adapter, db, err
 }
 
 var reMigrationDir = regexp.MustCompile(`[0-9]+-[\w\-]+$`)
 
 func getMigrationSubDirectories(options *AppOptions) ([]string, error) {
-
-	var files []string
-	err := filepath.Walk(options.MigrationsDir+"/", func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() && reMigrationDir.MatchString(path) {
-			files = append(files, info.Name())
-		}
-		return nil
-	})
+	files, err := options.source().Dirs(options.MigrationsDir)
 
 	// sort in DESC order
 	//sort.Sort(sort.StringSlice(files))
@@ -89,18 +81,8 @@ func getMigrationSubDirectories(options *AppOptions) ([]string, error) {
 
 var reSQLFile = regexp.MustCompile(`[\w\-]+.sql$`)
 
-func getSprocFiles(sprocsDir string) ([]string, error) {
-	var files []string
-	err := filepath.Walk(sprocsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && reSQLFile.MatchString(info.Name()) {
-			files = append(files, info.Name())
-		}
-		return nil
-	})
+func getSprocFiles(options *AppOptions, sprocsDir string) ([]string, error) {
+	files, err := options.source().Files(sprocsDir)
 
 	// sort in DESC order
 	//sort.Sort(sort.StringSlice(files))
@@ -216,13 +198,8 @@ func verifyMigrationsHistory(ctx *AppContext, localMigrations []*Migration, dbMi
 	return nil
 }
 
-func readFileText(filename string) (string, error) {
-	b, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return "", err
-	}
-
-	return string(b), nil
+func readFileText(options *AppOptions, filename string) (string, error) {
+	return options.source().ReadFile(filename)
 }
 
 // writeFileAll write text to a file. Subdirectories are created recursively like
@@ -241,7 +218,7 @@ func writeFileAll(filename string, b []byte) error {
 // an empty string.
 func readInitScript(options *AppOptions) string {
 	path := filepath.Join(options.MigrationsDir, "_init", "up.sql")
-	s, _ := readFileText(path)
+	s, _ := readFileText(options, path)
 	return s
 }
 
@@ -265,14 +242,19 @@ func askOption(prompt string, options []string) (string, error) {
 
 var reBatchSeparator = regexp.MustCompile(`(?m)^GO\n`)
 
-// Executes a script which may have a batch separator (default is GO). Filename
-// is used for error reporting
-func execScript(conn runner.Connection, script string) error {
+// Executes a script which may have a batch separator (default is GO).
+// Filename is used for error reporting. observer may be nil, in which case
+// it behaves like LogObserver{}.
+func execScript(ctx *AppContext, filename string, conn runner.Connection, script string, observer Observer) error {
 	statements := reBatchSeparator.Split(script, -1)
 	if len(statements) == 0 {
 		return nil
 	}
 
+	if observer == nil {
+		observer = LogObserver{}
+	}
+
 	for _, statement := range statements {
 		if statement == "" {
 			continue
@@ -282,13 +264,14 @@ func execScript(conn runner.Connection, script string) error {
 			return nil
 		}
 
+		observer.OnStatementStart(filename)
 		_, err := conn.SQL(statement).Exec()
+		if err != nil && strings.Contains(err.Error(), "no RowsAffected") {
+			err = nil
+		}
+		observer.OnStatementEnd(filename, err)
 		if err != nil {
-			if strings.Contains(err.Error(), "no RowsAffected") {
-				continue
-			}
-
-			logger.Error(sprintPQError(statement, err))
+			logger.Error(sprintPQError(ctx, filename, statement, err))
 			return err
 		}
 	}
@@ -296,15 +279,15 @@ func execScript(conn runner.Connection, script string) error {
 	return nil
 }
 
-func execFile(ctx *AppContext, conn runner.Connection, filename string) (string, error) {
+func execFile(ctx *AppContext, conn runner.Connection, filename string, observer Observer) (string, error) {
 	logger.Info("%s ... ", filename)
-	script, err := readFileText(filename)
+	script, err := readFileText(ctx.Options, filename)
 	if err != nil {
 		logger.Info("\n")
 		return "", err
 	}
 
-	err = execScript(conn, script)
+	err = execScript(ctx, filename, conn, script, observer)
 	if err != nil {
 		logger.Info("\n")
 		return "", err
@@ -313,13 +296,14 @@ func execFile(ctx *AppContext, conn runner.Connection, filename string) (string,
 	return script, nil
 }
 
-// runUpScripts run a migration's notx and up scripts
-func runUpScripts(ctx *AppContext, conn runner.Connection, migration *Migration) error {
+// runUpScripts run a migration's notx and up scripts. observer may be nil,
+// in which case it behaves like LogObserver{}.
+func runUpScripts(ctx *AppContext, conn runner.Connection, migration *Migration, observer Observer) error {
 	// notx.sql is not required
 	noTxFilename := scriptFilename(ctx.Options, migration, "notx.sql")
-	if _, err := os.Stat(noTxFilename); err == nil {
+	if _, err := ctx.Options.source().ReadFile(noTxFilename); err == nil {
 		// notx is an optional script
-		script, err := execFile(ctx, conn, noTxFilename)
+		script, err := execFile(ctx, conn, noTxFilename, observer)
 		if err != nil {
 			return err
 		}
@@ -330,12 +314,10 @@ func runUpScripts(ctx *AppContext, conn runner.Connection, migration *Migration)
 
 	// down.sql is not required
 	downFilename := scriptFilename(ctx.Options, migration, "down.sql")
-	if _, err := os.Stat(downFilename); err == nil {
-		downScript, err := readFileText(downFilename)
-		if err != nil {
-			return err
-		}
+	if downScript, err := readFileText(ctx.Options, downFilename); err == nil {
 		migration.DownScript = downScript
+	} else if reg, ok := dat.LookupMigration(migration.Name); ok && reg.Down != nil {
+		migration.DownScript = dat.SourceText(migration.Name, reg.DownSource)
 	}
 
 	tx, err := conn.Begin()
@@ -344,15 +326,31 @@ func runUpScripts(ctx *AppContext, conn runner.Connection, migration *Migration)
 	}
 	defer tx.AutoRollback()
 
-	upScript, err := execFile(ctx, conn, scriptFilename(ctx.Options, migration, "up.sql"))
-	if err != nil {
+	// a migration may be a Go function registered via AddMigration instead
+	// of an up.sql file
+	upFilename := scriptFilename(ctx.Options, migration, "up.sql")
+	if upScript, err := readFileText(ctx.Options, upFilename); err == nil {
+		logger.Info("%s ... ", upFilename)
+		if err := execScript(ctx, upFilename, tx, upScript, observer); err != nil {
+			logger.Info("\n")
+			return err
+		}
+		logger.Info("OK\n")
+		migration.UpScript = upScript
+	} else if hasGoMigration(migration.Name) {
+		if err := runGoUpScript(migration, tx); err != nil {
+			return err
+		}
+	} else {
 		return err
 	}
-	migration.UpScript = upScript
+
+	migration.UpChecksum = checksum(migration.UpScript)
+	migration.DownChecksum = checksum(migration.DownScript)
 
 	q := `
-		insert into dat__migrations (name, up_script, down_script, no_tx_script)
-		values ($1, $2, $3, $4);
+		insert into dat__migrations (name, up_script, down_script, no_tx_script, up_checksum, down_checksum, applied_at, applied_by)
+		values ($1, $2, $3, $4, $5, $6, now(), current_user);
 	`
 
 	_, err = tx.SQL(
@@ -361,6 +359,8 @@ func runUpScripts(ctx *AppContext, conn runner.Connection, migration *Migration)
 		migration.UpScript,
 		migration.DownScript,
 		migration.NoTransactionScript,
+		migration.UpChecksum,
+		migration.DownChecksum,
 	).Exec()
 	if err != nil {
 		return err
@@ -409,35 +409,6 @@ func parseSprocName(body string) string {
 	return ""
 }
 
-func sprintPQError(script string, err error) string {
-	if err == nil {
-		return ""
-	}
-
-	fmt.Println("DBG:00")
-	spew.Dump(err)
-	if e, ok := err.(*pq.Error); ok {
-		//. TODO need to show line number, column on syntax errors
-		// fmt.Println("Code", e.Code)
-		// fmt.Println("Column", e.Column)
-		// fmt.Println("Line", e.Line)
-		// fmt.Println("Position", e.Position)
-		// fmt.Println("Message", e.Message)
-		// fmt.Println("Detail", e.Detail)
-		// fmt.Println("Hint", e.Hint)
-		// fmt.Println("Severity", e.Severity)
-
-		fmt.Println("DBG:10")
-		if e.Position != "" {
-			line, col, _ := extractLineColumn(script, e.Position)
-			return fmt.Sprintf("[%s=%s] %s at line=%d col=%d\n", e.Severity, e.Code, e.Message, line, col)
-		}
-		return fmt.Sprintf("[PQ %s=%s] %s", e.Severity, e.Code, e.Message)
-	}
-
-	return ""
-}
-
 func extractLineColumn(script string, pos string) (int, int, error) {
 	position, err := strconv.Atoi(pos)
 	if err != nil {
@@ -459,12 +430,12 @@ func extractLineColumn(script string, pos string) (int, int, error) {
 			}
 			if i < max-1 {
 				line++
-				column = 0
+				column = 1
 			}
 		} else if ch == '\n' {
 			if i < max-1 {
 				line++
-				column = 0
+				column = 1
 			}
 		} else {
 			column++
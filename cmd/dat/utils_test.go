@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestExtractLineColumn(t *testing.T) {
+	cases := []struct {
+		name   string
+		script string
+		pos    string
+		line   int
+		col    int
+	}{
+		{
+			name:   "first column of first line",
+			script: "select 1;",
+			pos:    "0",
+			line:   1,
+			col:    1,
+		},
+		{
+			name:   "mid first line",
+			script: "select 1;",
+			pos:    "4",
+			line:   1,
+			col:    5,
+		},
+		{
+			name:   "column resets to 1 just after a newline",
+			script: "select 1;\nselect 2;",
+			pos:    "9",
+			line:   2,
+			col:    1,
+		},
+		{
+			name:   "mid second line",
+			script: "select 1;\nselect 2;",
+			pos:    "13",
+			line:   2,
+			col:    5,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			line, col, err := extractLineColumn(c.script, c.pos)
+			if err != nil {
+				t.Fatalf("extractLineColumn returned error: %v", err)
+			}
+			if line != c.line || col != c.col {
+				t.Errorf("extractLineColumn(%q, %q) = (%d, %d), want (%d, %d)", c.script, c.pos, line, col, c.line, c.col)
+			}
+		})
+	}
+}
+
+func TestMigrationFindIndexOf(t *testing.T) {
+	migrations := []*Migration{
+		{Name: "20200101-a"},
+		{Name: "20200102-b"},
+	}
+
+	if idx := migrationFindIndexOf(migrations, "20200102-b"); idx != 1 {
+		t.Errorf("migrationFindIndexOf = %d, want 1", idx)
+	}
+	if idx := migrationFindIndexOf(migrations, "missing"); idx != -1 {
+		t.Errorf("migrationFindIndexOf = %d, want -1", idx)
+	}
+}
@@ -0,0 +1,88 @@
+package dat
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+// GoMigrationFunc is a migration step implemented in Go rather than SQL. It
+// receives the same transactional runner.Connection used for up.sql/down.sql
+// so it can mix raw SQL with arbitrary Go code (e.g. calling out to a crypto
+// library to backfill a hashed column).
+type GoMigrationFunc func(tx runner.Connection) error
+
+// GoMigration is a registered Go migration pair for one migration name.
+type GoMigration struct {
+	Name string
+	Up   GoMigrationFunc
+	Down GoMigrationFunc
+
+	// UpSource and DownSource are the literal Go source of Up/Down (e.g.
+	// embedded into the caller's package with go:embed), used to detect
+	// drift: unlike up.sql/down.sql, dat can't read its own compiled code,
+	// so the caller supplies the source text it was built from.
+	UpSource   string
+	DownSource string
+}
+
+// goMigrations holds every migration registered via AddMigration, keyed by
+// migration name. It is populated by init() funcs in the application
+// embedding dat, before the dat CLI runs, mirroring goose/pop's Go
+// migration registries.
+var goMigrations = map[string]*GoMigration{}
+
+// AddMigration registers up and down functions for the migration folder
+// named name, so dat dispatches to them instead of requiring up.sql/down.sql
+// on disk. upSource/downSource are the literal source text of up/down,
+// hashed into the persisted up_script/down_script so edits are caught by
+// dat's checksum drift check; down and downSource may both be empty for an
+// irreversible migration.
+//
+// up and down are grouped together, followed by upSource and downSource
+// together, rather than interleaved as (up, upSource, down, downSource).
+// A GoMigrationFunc passed where a string is expected (or the reverse) fails
+// to compile either way; a swapped upSource/downSource still compiles.
+//
+// AddMigration lives in this importable package, rather than dat's own CLI,
+// so applications can call it from their own package (e.g. compiled into a
+// single binary alongside an embed.FS of migrations) to register Go
+// migrations dat's CLI doesn't ship with.
+func AddMigration(name string, up, down GoMigrationFunc, upSource, downSource string) {
+	goMigrations[name] = &GoMigration{
+		Name:       name,
+		Up:         up,
+		Down:       down,
+		UpSource:   upSource,
+		DownSource: downSource,
+	}
+}
+
+// LookupMigration returns the Go migration registered for name, if any.
+func LookupMigration(name string) (*GoMigration, bool) {
+	m, ok := goMigrations[name]
+	return m, ok
+}
+
+// SourceText returns the stable identifier persisted into
+// dat__migrations.up_script/down_script in place of SQL source: the
+// migration name plus a hash of its actual registered source, so an edit to
+// the Go function is visible to dat's checksum drift check the same way an
+// edit to up.sql/down.sql would be.
+func SourceText(name, source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return fmt.Sprintf("go:%s:%x", name, sum)
+}
+
+// MigrationNames returns registered Go migration names in ascending order,
+// useful for diagnostics.
+func MigrationNames() []string {
+	names := make([]string, 0, len(goMigrations))
+	for name := range goMigrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,63 @@
+package dat
+
+import (
+	"testing"
+
+	runner "github.com/mgutz/dat/sqlx-runner"
+)
+
+func TestAddMigrationLookup(t *testing.T) {
+	up := func(tx runner.Connection) error { return nil }
+	down := func(tx runner.Connection) error { return nil }
+
+	AddMigration("20200101-go-migration", up, down, "up source", "down source")
+
+	reg, ok := LookupMigration("20200101-go-migration")
+	if !ok {
+		t.Fatal("LookupMigration did not find the migration just registered")
+	}
+	if reg.Name != "20200101-go-migration" {
+		t.Errorf("reg.Name = %q, want %q", reg.Name, "20200101-go-migration")
+	}
+	if reg.UpSource != "up source" || reg.DownSource != "down source" {
+		t.Errorf("reg.UpSource/DownSource = %q/%q, want %q/%q", reg.UpSource, reg.DownSource, "up source", "down source")
+	}
+
+	if _, ok := LookupMigration("does-not-exist"); ok {
+		t.Error("LookupMigration found a migration that was never registered")
+	}
+}
+
+func TestSourceTextStableForSameSource(t *testing.T) {
+	a := SourceText("20200101-a", "same source")
+	b := SourceText("20200101-a", "same source")
+	if a != b {
+		t.Errorf("SourceText is not deterministic for the same input: %q != %q", a, b)
+	}
+
+	c := SourceText("20200101-a", "different source")
+	if a == c {
+		t.Error("SourceText did not change when source changed")
+	}
+}
+
+func TestMigrationNamesSorted(t *testing.T) {
+	noop := func(tx runner.Connection) error { return nil }
+	AddMigration("20200103-c", noop, nil, "c", "")
+	AddMigration("20200102-b", noop, nil, "b", "")
+
+	names := MigrationNames()
+	idxB := -1
+	idxC := -1
+	for i, name := range names {
+		switch name {
+		case "20200102-b":
+			idxB = i
+		case "20200103-c":
+			idxC = i
+		}
+	}
+	if idxB == -1 || idxC == -1 || idxB > idxC {
+		t.Errorf("MigrationNames() = %v, want 20200102-b before 20200103-c", names)
+	}
+}